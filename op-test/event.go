@@ -0,0 +1,131 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventKind discriminates the kind of a structured test-plan event. See Event.
+type EventKind string
+
+const (
+	// EventPlanStart marks entry into a Plan scope.
+	EventPlanStart EventKind = "PlanStart"
+	// EventSelect marks a parameter choice being made.
+	EventSelect EventKind = "Select"
+	// EventSkip marks a scope being skipped, e.g. by a plan filter or
+	// because no option was selected for a parameter.
+	EventSkip EventKind = "Skip"
+	// EventRunStart marks entry into a Run leaf.
+	EventRunStart EventKind = "RunStart"
+	// EventRunEnd marks completion of a Run leaf.
+	EventRunEnd EventKind = "RunEnd"
+	// EventExhaust marks a sub-test being spawned to cover one
+	// previously-unchosen parameter option.
+	EventExhaust EventKind = "Exhaust"
+)
+
+// Event is a single structured occurrence during plan discovery and
+// execution, analogous to cmd/internal/test2json's TestEvent but carrying
+// the parameter axis and exhaust structure that `go test -json` does not
+// expose. Only the fields relevant to Kind are populated.
+type Event struct {
+	Kind EventKind `json:"kind"`
+	Path []string  `json:"path"`
+
+	// Select
+	Name    string   `json:"name,omitempty"`
+	Options []string `json:"options,omitempty"`
+	Chosen  string   `json:"chosen,omitempty"`
+
+	// Skip
+	Reason string `json:"reason,omitempty"`
+
+	// RunStart / RunEnd
+	Status   string        `json:"status,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Exhaust
+	Parameter string `json:"parameter,omitempty"`
+	Option    string `json:"option,omitempty"`
+}
+
+type eventSinkCtxKey struct{}
+
+// WithEventSink returns a context that routes every Event emitted by plan
+// discovery and execution to sink. sink may be called concurrently, e.g.
+// from parallel exhaust sub-tests (see -op.parallel), and must be safe for
+// that.
+func WithEventSink(ctx context.Context, sink func(Event)) context.Context {
+	return context.WithValue(ctx, eventSinkCtxKey{}, sink)
+}
+
+// emitEvent delivers ev to the sink installed on ctx, if any.
+func emitEvent(ctx context.Context, ev Event) {
+	sink, ok := ctx.Value(eventSinkCtxKey{}).(func(Event))
+	if !ok || sink == nil {
+		return
+	}
+	sink(ev)
+}
+
+// fileEventSink is the process-wide, reference-counted state behind one
+// -op.events path: the underlying file is opened (and truncated) once per
+// process no matter how many Plan/RunPlan calls share that path, e.g. one
+// per TestXxx function in a package, and is only closed once every caller
+// that opened it has released it.
+type fileEventSink struct {
+	mu   sync.Mutex
+	f    *os.File
+	enc  *json.Encoder
+	refs int
+}
+
+var (
+	fileEventSinksMu sync.Mutex
+	fileEventSinks   = map[string]*fileEventSink{}
+)
+
+// newFileEventSink returns the shared sink for path, opening (and
+// truncating) the underlying file only the first time path is requested in
+// this process, so that multiple Plan/RunPlan calls writing to the same
+// -op.events path append to one NDJSON stream instead of each overwriting
+// the last. The returned sink is safe for concurrent use; closeFn must be
+// called exactly once per newFileEventSink call, and only actually closes
+// the file once every caller has done so.
+func newFileEventSink(path string) (sink func(Event), closeFn func() error, err error) {
+	fileEventSinksMu.Lock()
+	defer fileEventSinksMu.Unlock()
+
+	s, ok := fileEventSinks[path]
+	if !ok {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open -op.events file %q: %w", path, err)
+		}
+		s = &fileEventSink{f: f, enc: json.NewEncoder(f)}
+		fileEventSinks[path] = s
+	}
+	s.refs++
+
+	sink = func(ev Event) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = s.enc.Encode(ev)
+	}
+	closeFn = func() error {
+		fileEventSinksMu.Lock()
+		defer fileEventSinksMu.Unlock()
+		s.refs--
+		if s.refs > 0 {
+			return nil
+		}
+		delete(fileEventSinks, path)
+		return s.f.Close()
+	}
+	return sink, closeFn, nil
+}
@@ -0,0 +1,269 @@
+package op_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flags to pin, expand or exclude parameter options from the command line,
+// the same ergonomic knob godog gives for step/tag selection:
+//
+//	-op.param net=mainnet,sepolia      # only run these options (expands an axis)
+//	-op.param 'client=*'               # expand every offered option
+//	-op.exclude net=sepolia,client=reth   # skip this specific combination
+//	-op.matrix-file=matrix.yaml        # same, in bulk, from a file
+//
+// -op.param and -op.exclude may be repeated. With none set, every offered
+// option stays in play: the first is the inline default of a bare `go test`
+// and exhaust runs the others.
+//
+// The same selections can be made without flags, e.g. when the test binary
+// is invoked directly in CI, via semicolon-separated OP_TEST_PARAM and
+// OP_TEST_EXCLUDE environment variables, which are additive with the flags,
+// and via OP_TEST_MATRIX_FILE, which is only read when -op.matrix-file is
+// not set.
+var (
+	opParamFlag      = flagMap{}
+	opExcludeFlag    = flagExcludes{}
+	opMatrixFileFlag = flag.String("op.matrix-file", "", "path to a YAML file with `params` and `exclude` entries (see -op.param/-op.exclude)")
+	opParallelFlag   = flag.Int("op.parallel", 0, "if > 0, exhaust-generated sub-tests call t.Parallel() (Planner.RunParallel leaves always do); cooperates with -test.parallel")
+	opEventsFlag     = flag.String("op.events", "", "write an NDJSON stream of PlanStart/Select/Skip/RunStart/RunEnd/Exhaust events to this path")
+	opReplayFlag     = flag.String("op.replay", "", "replay exactly the parameter combination from a previous Testing.Snapshot(), e.g. net=mainnet;client=geth;fork=cancun")
+)
+
+func init() {
+	flag.Var(&opParamFlag, "op.param", "pin or expand a parameter, e.g. net=mainnet,sepolia or client=*; repeatable")
+	flag.Var(&opExcludeFlag, "op.exclude", "exclude a combination of parameter values, e.g. net=sepolia,client=reth; repeatable")
+}
+
+// flagMap collects repeated -op.param flags into name -> requested options.
+type flagMap map[string][]string
+
+func (m flagMap) String() string {
+	var parts []string
+	for name, opts := range m {
+		parts = append(parts, name+"="+strings.Join(opts, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m flagMap) Set(v string) error {
+	name, opts, err := parseParamFlag(v)
+	if err != nil {
+		return err
+	}
+	m[name] = append(m[name], opts...)
+	return nil
+}
+
+func parseParamFlag(v string) (name string, options []string, err error) {
+	name, rest, ok := strings.Cut(v, "=")
+	if !ok || name == "" || rest == "" {
+		return "", nil, fmt.Errorf("invalid -op.param %q, expected name=value[,value...]", v)
+	}
+	return name, strings.Split(rest, ","), nil
+}
+
+// exclusion is one -op.exclude rule: every one of these (name, value) pairs
+// must match the parameters chosen (or being chosen) so far for the
+// combination to be excluded.
+type exclusion map[string]string
+
+// matches reports whether this exclusion rule applies to candidate being
+// considered for parameter name, given ctx's already-resolved parameters.
+// A constraint on a parameter that hasn't been resolved yet cannot be
+// evaluated, and is treated as not matching.
+func (e exclusion) matches(ctx context.Context, name, candidate string) bool {
+	for k, v := range e {
+		if k == name {
+			if candidate != v {
+				return false
+			}
+			continue
+		}
+		val, ok := ctx.Value(parameterCtxKey(k)).(string)
+		if !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+type flagExcludes []exclusion
+
+func (e *flagExcludes) String() string {
+	return fmt.Sprintf("%v", []exclusion(*e))
+}
+
+func (e *flagExcludes) Set(v string) error {
+	rule, err := parseExcludeFlag(v)
+	if err != nil {
+		return err
+	}
+	*e = append(*e, rule)
+	return nil
+}
+
+func parseExcludeFlag(v string) (exclusion, error) {
+	rule := exclusion{}
+	for _, part := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid -op.exclude %q, expected name=value[,name=value...]", v)
+		}
+		rule[name] = value
+	}
+	return rule, nil
+}
+
+// matrixFile is the shape of the -op.matrix-file YAML document.
+type matrixFile struct {
+	Params  map[string][]string `yaml:"params"`
+	Exclude []map[string]string `yaml:"exclude"`
+}
+
+func loadMatrixFile(path string) (matrixFile, error) {
+	var m matrixFile
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("failed to read matrix file %q: %w", path, err)
+	}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("failed to parse matrix file %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// cliParameterSelector is the ParameterSelector installed by Plan by
+// default. With no -op.param/-op.exclude/-op.matrix-file given, it returns
+// every offered option: a bare `go test` takes the first inline and exhaust
+// (see planner.go) expands the rest.
+type cliParameterSelector struct {
+	pins     flagMap
+	excludes []exclusion
+}
+
+var _ ParameterSelector = (*cliParameterSelector)(nil)
+
+// newParameterSelector builds the ParameterSelector to install for a Plan
+// or RunPlan call: -op.replay takes exclusive priority over
+// -op.param/-op.exclude/-op.matrix-file, since its entire point is to
+// reproduce one specific recorded combination.
+func newParameterSelector() (ParameterSelector, error) {
+	if *opReplayFlag != "" {
+		recorded, err := ParseSnapshot(*opReplayFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -op.replay: %w", err)
+		}
+		return &replayParameterSelector{recorded: recorded}, nil
+	}
+	return newCLIParameterSelector()
+}
+
+// newCLIParameterSelector builds the selector from the registered
+// -op.param/-op.exclude/-op.matrix-file flags (flag.Parse must already have
+// run, as it has by the time a *testing.T exists).
+func newCLIParameterSelector() (*cliParameterSelector, error) {
+	pins := flagMap{}
+	for name, opts := range opParamFlag {
+		pins[name] = append(pins[name], opts...)
+	}
+	excludes := append([]exclusion{}, opExcludeFlag...)
+
+	if env := os.Getenv("OP_TEST_PARAM"); env != "" {
+		for _, part := range strings.Split(env, ";") {
+			name, opts, err := parseParamFlag(part)
+			if err != nil {
+				return nil, fmt.Errorf("OP_TEST_PARAM: %w", err)
+			}
+			pins[name] = append(pins[name], opts...)
+		}
+	}
+	if env := os.Getenv("OP_TEST_EXCLUDE"); env != "" {
+		for _, part := range strings.Split(env, ";") {
+			rule, err := parseExcludeFlag(part)
+			if err != nil {
+				return nil, fmt.Errorf("OP_TEST_EXCLUDE: %w", err)
+			}
+			excludes = append(excludes, rule)
+		}
+	}
+
+	matrixPath := *opMatrixFileFlag
+	if matrixPath == "" {
+		matrixPath = os.Getenv("OP_TEST_MATRIX_FILE")
+	}
+	if matrixPath != "" {
+		m, err := loadMatrixFile(matrixPath)
+		if err != nil {
+			return nil, err
+		}
+		for name, opts := range m.Params {
+			pins[name] = append(pins[name], opts...)
+		}
+		for _, rule := range m.Exclude {
+			excludes = append(excludes, rule)
+		}
+	}
+	return &cliParameterSelector{pins: pins, excludes: excludes}, nil
+}
+
+// expandPin returns every option matching one of the patterns in pin
+// ("*" or a glob matches any number of options), preserving options' order.
+func expandPin(pin []string, options []string) []string {
+	var out []string
+	seen := make(map[string]struct{}, len(options))
+	for _, opt := range options {
+		for _, pattern := range pin {
+			if pattern == opt {
+				// exact match
+			} else if ok, _ := path.Match(pattern, opt); !ok {
+				continue
+			}
+			if _, dup := seen[opt]; !dup {
+				seen[opt] = struct{}{}
+				out = append(out, opt)
+			}
+			break
+		}
+	}
+	return out
+}
+
+// Select implements ParameterSelector.Select
+func (s *cliParameterSelector) Select(ctx context.Context, name string, options []string) []string {
+	candidates := options
+	pin, pinned := s.pins[name]
+	if pinned {
+		candidates = expandPin(pin, options)
+	}
+
+	var out []string
+	for _, opt := range candidates {
+		excluded := false
+		for _, ex := range s.excludes {
+			if ex.matches(ctx, name, opt) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			out = append(out, opt)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	// Whether pinned or bare, every surviving candidate is returned: the
+	// first becomes the inline default (see testImpl.Select), the rest are
+	// registered for exhaust (see testImpl.exhaust) to fan out afterwards.
+	// -op.param only narrows which options are in play; it does not collapse
+	// an axis down to a single default the way a bare invocation used to.
+	return out
+}
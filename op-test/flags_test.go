@@ -0,0 +1,100 @@
+package op_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseParamFlag(t *testing.T) {
+	name, opts, err := parseParamFlag("net=mainnet,sepolia")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "net" || !reflect.DeepEqual(opts, []string{"mainnet", "sepolia"}) {
+		t.Fatalf("got name=%q opts=%v", name, opts)
+	}
+
+	for _, invalid := range []string{"net", "=mainnet", "net="} {
+		if _, _, err := parseParamFlag(invalid); err == nil {
+			t.Fatalf("expected error for invalid -op.param %q", invalid)
+		}
+	}
+}
+
+func TestParseExcludeFlag(t *testing.T) {
+	rule, err := parseExcludeFlag("net=sepolia,client=reth")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(rule, exclusion{"net": "sepolia", "client": "reth"}) {
+		t.Fatalf("got rule=%v", rule)
+	}
+
+	for _, invalid := range []string{"net", "net=sepolia,client"} {
+		if _, err := parseExcludeFlag(invalid); err == nil {
+			t.Fatalf("expected error for invalid -op.exclude %q", invalid)
+		}
+	}
+}
+
+func TestExpandPin(t *testing.T) {
+	options := []string{"geth", "reth", "erigon"}
+	if got := expandPin([]string{"reth"}, options); !reflect.DeepEqual(got, []string{"reth"}) {
+		t.Fatalf("exact pin: got %v", got)
+	}
+	if got := expandPin([]string{"*"}, options); !reflect.DeepEqual(got, options) {
+		t.Fatalf("wildcard pin: got %v", got)
+	}
+	if got := expandPin([]string{"g*", "erigon"}, options); !reflect.DeepEqual(got, []string{"geth", "erigon"}) {
+		t.Fatalf("glob pin: got %v", got)
+	}
+}
+
+func TestExclusionMatches(t *testing.T) {
+	ctx := context.WithValue(context.Background(), parameterCtxKey("net"), "sepolia")
+	ex := exclusion{"net": "sepolia", "client": "reth"}
+
+	if !ex.matches(ctx, "client", "reth") {
+		t.Fatalf("expected match when every constraint is satisfied")
+	}
+	if ex.matches(ctx, "client", "geth") {
+		t.Fatalf("expected no match when the candidate itself violates the rule")
+	}
+
+	unresolvedCtx := context.Background()
+	if ex.matches(unresolvedCtx, "client", "reth") {
+		t.Fatalf("expected no match when a constrained parameter hasn't been resolved yet")
+	}
+}
+
+// TestCLIParameterSelectorSelectUnpinned guards against a prior regression
+// where the bare (unpinned) path truncated its result to a single option,
+// which made exhaust (see cartesian) unable to ever fan out that axis.
+func TestCLIParameterSelectorSelectUnpinned(t *testing.T) {
+	s := &cliParameterSelector{}
+	got := s.Select(context.Background(), "net", []string{"mainnet", "sepolia"})
+	want := []string{"mainnet", "sepolia"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bare invocation: got %v, want %v", got, want)
+	}
+}
+
+func TestCLIParameterSelectorSelectPinned(t *testing.T) {
+	s := &cliParameterSelector{pins: flagMap{"net": {"sepolia"}}}
+	got := s.Select(context.Background(), "net", []string{"mainnet", "sepolia"})
+	want := []string{"sepolia"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pinned invocation: got %v, want %v", got, want)
+	}
+}
+
+func TestCLIParameterSelectorSelectExcluded(t *testing.T) {
+	ctx := context.WithValue(context.Background(), parameterCtxKey("net"), "sepolia")
+	s := &cliParameterSelector{excludes: []exclusion{{"net": "sepolia", "client": "reth"}}}
+	got := s.Select(ctx, "client", []string{"geth", "reth"})
+	want := []string{"geth"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("excluded invocation: got %v, want %v", got, want)
+	}
+}
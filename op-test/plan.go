@@ -0,0 +1,197 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// PlanNode is a single node of a recorded test plan: either a branch opened
+// by Plan (with children and/or parameter axes selected along its default
+// path) or a leaf opened by Run.
+type PlanNode struct {
+	Name       string               `json:"name"`
+	Leaf       bool                 `json:"leaf,omitempty"`
+	Parameters []parameterSelection `json:"parameters,omitempty"`
+	Children   []*PlanNode          `json:"children,omitempty"`
+}
+
+// WritePlan serializes a recorded plan tree as indented JSON.
+func WritePlan(w io.Writer, root *PlanNode) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(root)
+}
+
+// planMatchesFilter reports whether filter accepts the hierarchical path of
+// at least one leaf in the tree rooted at node.
+func planMatchesFilter(node *PlanNode, prefix []string, filter func(path []string) bool) bool {
+	path := append(append([]string{}, prefix...), node.Name)
+	if node.Leaf && filter(path) {
+		return true
+	}
+	for _, child := range node.Children {
+		if planMatchesFilter(child, path, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanRecorder is a Planner that walks Plan/Run calls without executing any
+// Run bodies, and records the resulting scopes and parameter axes into a
+// PlanNode tree. Use Plan(t, fn, Record(w)) to drive it from a regular test.
+type PlanRecorder struct {
+	node *PlanNode
+	ctx  context.Context
+}
+
+var _ Planner = (*PlanRecorder)(nil)
+
+func newPlanRecorder(ctx context.Context, name string) *PlanRecorder {
+	return &PlanRecorder{node: &PlanNode{Name: name}, ctx: ctx}
+}
+
+// Ctx implements Testing.Ctx
+func (r *PlanRecorder) Ctx() context.Context { return r.ctx }
+
+// Logger implements Testing.Logger. Recording never produces log output, so
+// this intentionally returns a discarding root logger.
+func (r *PlanRecorder) Logger() log.Logger { return log.Root() }
+
+// Parameter implements Testing.Parameter
+func (r *PlanRecorder) Parameter(name string) (value string, ok bool) {
+	v := r.ctx.Value(parameterCtxKey(name))
+	if v == nil {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// Snapshot implements Testing.Snapshot
+func (r *PlanRecorder) Snapshot() string { return snapshotString(r.ctx) }
+
+// Select implements Testing.Select. Unlike testImpl.Select, it never
+// consults a ParameterSelector: recording always walks every option, with
+// the first one becoming the default path to keep recording down before
+// exhaust fans out the rest.
+func (r *PlanRecorder) Select(name string, options ...string) string {
+	if current := r.ctx.Value(parameterCtxKey(name)); current != nil {
+		return current.(string)
+	}
+	r.node.Parameters = append(r.node.Parameters, parameterSelection{Name: name, Options: options})
+	r.ctx = context.WithValue(r.ctx, parameterCtxKey(name), options[0])
+	r.ctx = withParamName(r.ctx, name)
+	return options[0]
+}
+
+// Plan implements Planner.Plan
+func (r *PlanRecorder) Plan(name string, fn func(t Planner)) {
+	child := newPlanRecorder(r.ctx, name)
+	fn(child)
+	child.exhaust(fn)
+	r.node.Children = append(r.node.Children, child.node)
+}
+
+// Run implements Planner.Run. The leaf body is never executed while
+// recording: only its existence as a scope is captured.
+func (r *PlanRecorder) Run(name string, fn func(t Executor)) {
+	r.node.Children = append(r.node.Children, &PlanNode{Name: name, Leaf: true})
+}
+
+// RunParallel implements Planner.RunParallel. Recording does not execute
+// anything, so parallelism makes no difference here: it is recorded exactly
+// like Run.
+func (r *PlanRecorder) RunParallel(name string, fn func(t Executor)) {
+	r.Run(name, fn)
+}
+
+// exhaust mirrors testImpl.exhaust: it fans out one sibling node per
+// unchosen combination of options across every parameter selected along the
+// default path, so recorded plans reflect the same N x M expansion that
+// execution will perform.
+func (r *PlanRecorder) exhaust(fn func(t Planner)) {
+	sels := r.node.Parameters
+	if len(sels) == 0 {
+		return
+	}
+	for _, combo := range cartesian(sels) {
+		isDefault := true
+		subCtx := r.ctx
+		var name strings.Builder
+		name.WriteString("exhaust")
+		for i, opt := range combo {
+			if current, ok := r.Parameter(sels[i].Name); ok && current != opt {
+				isDefault = false
+			}
+			subCtx = context.WithValue(subCtx, parameterCtxKey(sels[i].Name), opt)
+			subCtx = withParamName(subCtx, sels[i].Name)
+			name.WriteString("_" + sels[i].Name + "_" + opt)
+		}
+		if isDefault {
+			continue
+		}
+		child := newPlanRecorder(subCtx, name.String())
+		fn(child)
+		child.exhaust(fn)
+		r.node.Children = append(r.node.Children, child.node)
+	}
+}
+
+// RunPlan executes fn in immediate mode, restricted to the leaves whose
+// hierarchical path (the leaf's *testing.T name split on "/", as produced by
+// Plan/Run/exhaust, e.g.
+// []string{"TestFoo", "default", "exhaust_net_sepolia", "connects"}) passes
+// filter.
+// plan is used to validate that filter actually matches a leaf recorded in
+// that tree before anything runs, so a stale or mistyped filter fails loudly
+// instead of silently executing nothing; RunPlan itself always drives fn
+// directly, so the executed tree stays in sync even if fn changed since
+// plan was recorded.
+//
+// This is how a CI worker executes the one leaf (or shard of leaves) it was
+// assigned.
+func RunPlan(t *testing.T, plan *PlanNode, filter func(path []string) bool, fn func(Planner)) {
+	if filter != nil {
+		if plan == nil {
+			t.Fatalf("RunPlan requires a recorded plan to validate filter against")
+		}
+		if !planMatchesFilter(plan, nil, filter) {
+			t.Fatalf("plan filter matches no leaf in the recorded plan %q", plan.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ctx = withPlanFilter(ctx, filter)
+
+	selector, err := newParameterSelector()
+	if err != nil {
+		t.Fatalf("failed to configure parameter selection: %v", err)
+	}
+	ctx = context.WithValue(ctx, parameterManagerCtxKey{}, selector)
+	ctx = withParallelMode(ctx, *opParallelFlag > 0)
+
+	if *opEventsFlag != "" {
+		sink, closeFn, err := newFileEventSink(*opEventsFlag)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		t.Cleanup(func() { _ = closeFn() })
+		ctx = WithEventSink(ctx, sink)
+	}
+
+	imp := &testImpl{
+		T:      t,
+		ctx:    ctx,
+		logLvl: slog.LevelError,
+	}
+	imp.Plan("default", fn)
+	imp.exhaust(fn)
+}
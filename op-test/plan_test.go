@@ -0,0 +1,66 @@
+package op_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestPlanRecordIncludesDefaultScope guards against a prior regression where
+// Record's recorded tree had no equivalent of the "default" scope that
+// execution always wraps fn in (via imp.Plan("default", fn)), so a filter
+// built from a recorded plan's own path shape matched nothing at runtime.
+func TestPlanRecordIncludesDefaultScope(t *testing.T) {
+	fn := func(pl Planner) {
+		pl.Run("connects", func(_ Executor) {})
+	}
+
+	var buf bytes.Buffer
+	Plan(t, fn, Record(&buf))
+
+	var root PlanNode
+	if err := json.Unmarshal(buf.Bytes(), &root); err != nil {
+		t.Fatalf("failed to parse recorded plan: %v", err)
+	}
+	if len(root.Children) != 1 || root.Children[0].Name != "default" {
+		t.Fatalf("expected a single \"default\" child matching the execution branch, got %+v", root.Children)
+	}
+
+	def := root.Children[0]
+	if len(def.Children) != 1 || def.Children[0].Name != "connects" || !def.Children[0].Leaf {
+		t.Fatalf("expected \"default\" to contain the recorded \"connects\" leaf, got %+v", def.Children)
+	}
+}
+
+// TestRunPlanFilterMatchesRealPath confirms that a filter written against
+// the shape RunPlan's own doc comment documents (e.g.
+// []string{t.Name(), "default", "connects"}) actually matches the real sub-test path
+// produced by execution, end to end through RunPlan.
+func TestRunPlanFilterMatchesRealPath(t *testing.T) {
+	var ran bool
+	fn := func(pl Planner) {
+		pl.Run("connects", func(_ Executor) {
+			ran = true
+		})
+	}
+
+	plan := &PlanNode{
+		Name: t.Name(),
+		Children: []*PlanNode{
+			{Name: "default", Children: []*PlanNode{
+				{Name: "connects", Leaf: true},
+			}},
+		},
+	}
+
+	filter := func(path []string) bool {
+		return strings.Join(path, "/") == strings.Join([]string{t.Name(), "default", "connects"}, "/")
+	}
+
+	RunPlan(t, plan, filter, fn)
+
+	if !ran {
+		t.Fatalf("RunPlan did not execute the leaf a path-shape-correct filter selected")
+	}
+}
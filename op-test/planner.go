@@ -2,10 +2,14 @@ package op_test
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
 	"slices"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"golang.org/x/exp/slog"
 
@@ -14,14 +18,79 @@ import (
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
 )
 
+// planModeEnv, when set to a non-empty value, switches Plan into deferred
+// (recording) mode without requiring call-sites to change, e.g. for CI:
+//
+//	OP_TEST_PLAN=1 go test ./... -run TestFoo
+const planModeEnv = "OP_TEST_PLAN"
+
+// planConfig controls whether Plan walks the test tree immediately (the
+// default) or only records it. See PlanOption and Record.
+type planConfig struct {
+	record bool
+	out    io.Writer
+}
+
+// PlanOption configures a Plan call. See Record.
+type PlanOption func(cfg *planConfig)
+
+// Record switches Plan into deferred mode: instead of executing any Run
+// bodies, it walks the Plan/Run structure and parameter axes and writes the
+// resulting PlanNode tree to w as JSON (see WritePlan). A later process can
+// feed that tree to RunPlan to execute one leaf (or a shard of leaves).
+func Record(w io.Writer) PlanOption {
+	return func(cfg *planConfig) {
+		cfg.record = true
+		cfg.out = w
+	}
+}
+
 // Plan is the default entry-point to use for op-test tests.
 // It wraps the Go test framework to provide test utils and parametrization features.
-func Plan(t *testing.T, fn func(t Planner)) {
+func Plan(t *testing.T, fn func(t Planner), opts ...PlanOption) {
+	cfg := &planConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if !cfg.record {
+		cfg.record = os.Getenv(planModeEnv) != ""
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
 
-	var selector ParameterSelector
+	selector, err := newParameterSelector()
+	if err != nil {
+		t.Fatalf("failed to configure parameter selection: %v", err)
+	}
 	ctx = context.WithValue(ctx, parameterManagerCtxKey{}, selector)
+	ctx = withParallelMode(ctx, *opParallelFlag > 0)
+
+	if *opEventsFlag != "" {
+		sink, closeFn, err := newFileEventSink(*opEventsFlag)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		t.Cleanup(func() { _ = closeFn() })
+		ctx = WithEventSink(ctx, sink)
+	}
+
+	if cfg.record {
+		root := newPlanRecorder(ctx, t.Name())
+		// Mirror the "default" scope that the execution branch below always
+		// wraps fn in (via imp.Plan("default", fn)), so a recorded leaf's
+		// path has the same shape as the real *testing.T path RunPlan's
+		// filter is matched against.
+		root.Plan("default", fn)
+		out := cfg.out
+		if out == nil {
+			out = os.Stdout
+		}
+		if err := WritePlan(out, root.node); err != nil {
+			t.Fatalf("failed to write test plan: %v", err)
+		}
+		return
+	}
 
 	imp := &testImpl{
 		T:      t,
@@ -34,9 +103,40 @@ func Plan(t *testing.T, fn func(t Planner)) {
 
 type parameterCtxKey string
 
+// planFilterCtxKey carries an optional predicate (set by RunPlan) that
+// restricts execution to a subset of leaves, identified by their
+// hierarchical *testing.T name.
+type planFilterCtxKey struct{}
+
+func withPlanFilter(ctx context.Context, filter func(path []string) bool) context.Context {
+	if filter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, planFilterCtxKey{}, filter)
+}
+
+// checkPlanFilter reports whether the current sub-test should run. If a
+// plan filter is active and rejects the sub-test's path, it is skipped and
+// checkPlanFilter returns false.
+func checkPlanFilter(t *testing.T, ctx context.Context) bool {
+	v := ctx.Value(planFilterCtxKey{})
+	if v == nil {
+		return true
+	}
+	filter := v.(func(path []string) bool)
+	path := strings.Split(t.Name(), "/")
+	if !filter(path) {
+		const reason = "skipped: not selected by test-plan filter"
+		emitEvent(ctx, Event{Kind: EventSkip, Path: path, Reason: reason})
+		t.Skip(reason)
+		return false
+	}
+	return true
+}
+
 type parameterSelection struct {
-	name    string
-	options []string
+	Name    string   `json:"name"`
+	Options []string `json:"options"`
 }
 
 // testImpl wraps the regular Go test framework to implement the Testing interface.
@@ -55,8 +155,9 @@ type testImpl struct {
 	loggerOnce sync.Once
 	logger     log.Logger
 
-	// First-seen parameterSelection, which can be exhausted at the end of the test.
-	parameterSelection *parameterSelection
+	// Every parameterSelection seen along the default path of this scope,
+	// in call order, which can be exhausted at the end of the test.
+	parameterSelections []parameterSelection
 }
 
 var _ Planner = (*testImpl)(nil)
@@ -88,35 +189,80 @@ func (imp *testImpl) Parameter(name string) (value string, ok bool) {
 
 // Run implements Planner.Run
 func (imp *testImpl) Run(name string, fn func(t Executor)) {
-	// TODO check if in immediate (execute now) or deferred (persist test-plan) mode
+	imp.runCtx(name, false, fn)
+}
+
+// RunParallel implements Planner.RunParallel
+func (imp *testImpl) RunParallel(name string, fn func(t Executor)) {
+	imp.runCtx(name, true, fn)
+}
 
+// runCtx runs a leaf sub-test, optionally marking it parallel.
+func (imp *testImpl) runCtx(name string, parallel bool, fn func(t Executor)) {
 	ctx := imp.Ctx()
 
-	// immediate
 	imp.T.Run(name, func(t *testing.T) {
+		if !checkPlanFilter(t, ctx) {
+			return
+		}
+		if parallel {
+			t.Parallel()
+		}
 		ctx, cancel := context.WithCancel(ctx)
 		t.Cleanup(cancel)
 
+		path := strings.Split(t.Name(), "/")
+		start := time.Now()
+		emitEvent(ctx, Event{Kind: EventRunStart, Path: path})
+		defer func() {
+			emitEvent(ctx, Event{Kind: EventRunEnd, Path: path, Status: runStatus(t), Duration: time.Since(start)})
+		}()
+
 		subScope := &testImpl{
 			T:      t,
 			ctx:    ctx,
 			logLvl: imp.logLvl,
 		}
+		t.Cleanup(func() {
+			if t.Failed() {
+				t.Logf("to reproduce this failure, rerun with -op.replay=%s", subScope.Snapshot())
+			}
+		})
 		fn(subScope)
 	})
 }
 
+// runStatus summarizes a leaf *testing.T's outcome for EventRunEnd.
+func runStatus(t *testing.T) string {
+	switch {
+	case t.Failed():
+		return "fail"
+	case t.Skipped():
+		return "skip"
+	default:
+		return "pass"
+	}
+}
+
 // Plan implements Planner.Plan
 func (imp *testImpl) Plan(name string, fn func(t Planner)) {
-	imp.planCtx(imp.Ctx(), name, fn)
+	imp.planCtx(imp.Ctx(), name, fn, false)
 }
 
-// planCtx runs a sub-test with a custom context
-func (imp *testImpl) planCtx(ctx context.Context, name string, fn func(t Planner)) {
+// planCtx runs a sub-test with a custom context, optionally marking it
+// parallel (used by exhaust, see -op.parallel).
+func (imp *testImpl) planCtx(ctx context.Context, name string, fn func(t Planner), parallel bool) {
 	imp.T.Run(name, func(t *testing.T) {
+		// The plan filter selects leaves only (see runCtx): a Plan scope's own
+		// path is a prefix of its leaves' paths and is never filtered itself.
+		if parallel {
+			t.Parallel()
+		}
 		ctx, cancel := context.WithCancel(ctx)
 		t.Cleanup(cancel)
 
+		emitEvent(ctx, Event{Kind: EventPlanStart, Path: strings.Split(t.Name(), "/")})
+
 		subScope := &testImpl{
 			T:      t,
 			ctx:    ctx,
@@ -129,36 +275,98 @@ func (imp *testImpl) planCtx(ctx context.Context, name string, fn func(t Planner
 	})
 }
 
-// exhaust reviews if any options were seen in the current test-scope, and then exhausts these.
+// exhaust reviews every parameter seen in the current test-scope, and then
+// runs a sub-test for every combination of options across all of them
+// (minus the combination already covered by the default path), so that two
+// independent axes (e.g. network topology x EL client) produce the full
+// N x M set of variants instead of just N+M.
 func (imp *testImpl) exhaust(fn func(t Planner)) {
-	if imp.parameterSelection == nil { // no parameters to exhaust
+	sels := imp.parameterSelections
+	if len(sels) == 0 { // no parameters to exhaust
 		return
 	}
 
-	ctx := imp.Ctx()
-	for _, opt := range imp.parameterSelection.options {
-		key := parameterCtxKey(imp.parameterSelection.name)
-
-		// If choice already matches the context, then we already made it in the default path.
-		current := ctx.Value(key)
-		if current == nil {
-			imp.T.Fatalf("test framework error: selecting %q, "+
-				"but exhaust-path is not running after default path", imp.parameterSelection.name)
+	base := imp.Ctx()
+	parallel := parallelModeEnabled(base)
+	path := strings.Split(imp.T.Name(), "/")
+	for _, combo := range cartesian(sels) {
+		isDefault := true
+		subCtx := base
+		var name strings.Builder
+		var diverged []parameterSelection
+		name.WriteString("exhaust")
+		for i, opt := range combo {
+			key := parameterCtxKey(sels[i].Name)
+
+			// If choice already matches the context, then we already made it in the default path.
+			current := base.Value(key)
+			if current == nil {
+				imp.T.Fatalf("test framework error: selecting %q, "+
+					"but exhaust-path is not running after default path", sels[i].Name)
+			}
+			if current.(string) != opt {
+				isDefault = false
+				diverged = append(diverged, parameterSelection{Name: sels[i].Name, Options: []string{opt}})
+			}
+			subCtx = context.WithValue(subCtx, key, opt)
+			name.WriteString("_" + sels[i].Name + "_" + opt)
 		}
-		if current.(string) == opt {
+		if isDefault {
 			continue
 		}
 
-		// Run a sub-test that overrides the default choice we may have made (if any).
-		subCtx := context.WithValue(ctx, key, opt)
-		imp.planCtx(subCtx, "exhaust_"+imp.parameterSelection.name+"_"+opt, fn)
+		for _, d := range diverged {
+			emitEvent(base, Event{Kind: EventExhaust, Path: path, Parameter: d.Name, Option: d.Options[0]})
+		}
+
+		// Run a sub-test that overrides the default choices we may have made (if any).
+		imp.planCtx(subCtx, name.String(), fn, parallel)
 	}
 }
 
+// parallelCtxKey carries whether exhaust-generated sub-tests should call
+// t.Parallel(), as set by the -op.parallel=N flag (N > 0 enables it). It
+// cooperates with the standard -test.parallel worker cap: t.Parallel() only
+// marks a sub-test as eligible to run concurrently, Go's test runner still
+// enforces -test.parallel as the concurrency ceiling.
+type parallelCtxKey struct{}
+
+func withParallelMode(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, parallelCtxKey{}, true)
+}
+
+func parallelModeEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(parallelCtxKey{}).(bool)
+	return enabled
+}
+
+// cartesian returns every combination of one option per selection, in the
+// same axis order as sels.
+func cartesian(sels []parameterSelection) [][]string {
+	combos := [][]string{{}}
+	for _, sel := range sels {
+		next := make([][]string, 0, len(combos)*len(sel.Options))
+		for _, combo := range combos {
+			for _, opt := range sel.Options {
+				next = append(next, append(append([]string{}, combo...), opt))
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
 // selected registers that a set of options was available for a named parameter,
 // and registers the first option as chosen.
 // It is invalid to signal an empty set of selected options.
 // It is invalid to signal selected options for a parameter that was already selected.
+// The caller must already hold imp.ctxLock for writing: selected mutates
+// imp.ctx directly, and with exhaust/RunParallel sub-tests now able to run
+// concurrently with their siblings, that mutation is only safe because
+// Select (the sole caller) holds the lock for its whole body.
 func (imp *testImpl) selected(name string, options ...string) {
 	if len(options) == 0 {
 		imp.T.Fatalf("cannot signal empty set of options of type %q", name)
@@ -168,8 +376,14 @@ func (imp *testImpl) selected(name string, options ...string) {
 		imp.T.Fatalf("test signaled options of type %q, but an option already selected: %q",
 			name, current.(string))
 	}
-	imp.parameterSelection = &parameterSelection{name: name, options: options}
+	imp.parameterSelections = append(imp.parameterSelections, parameterSelection{Name: name, Options: options})
 	imp.ctx = context.WithValue(imp.ctx, parameterCtxKey(name), options[0])
+	imp.ctx = withParamName(imp.ctx, name)
+}
+
+// Snapshot implements Testing.Snapshot
+func (imp *testImpl) Snapshot() string {
+	return snapshotString(imp.Ctx())
 }
 
 // Select implements Testing.Select
@@ -190,8 +404,10 @@ func (imp *testImpl) Select(name string, options ...string) string {
 	// get the parameter selector
 	selector := imp.ctx.Value(parameterManagerCtxKey{}).(ParameterSelector)
 	// select what option(s) we should go with
-	selectedOptions := selector.Select(name, options)
+	selectedOptions := selector.Select(imp.ctx, name, options)
 	if len(selectedOptions) == 0 {
+		reason := fmt.Sprintf("none of the options for parameter %q were selected", name)
+		emitEvent(imp.ctx, Event{Kind: EventSkip, Path: strings.Split(imp.T.Name(), "/"), Reason: reason})
 		imp.T.Skipf("None of the options for parameter %q where selected, skipping test!", name)
 	}
 	if !hasWildcard {
@@ -208,6 +424,10 @@ func (imp *testImpl) Select(name string, options ...string) string {
 	}
 	// register what options we selected
 	imp.selected(name, selectedOptions...)
+	emitEvent(imp.ctx, Event{
+		Kind: EventSelect, Path: strings.Split(imp.T.Name(), "/"),
+		Name: name, Options: options, Chosen: selectedOptions[0],
+	})
 	// return the option we went with as default
 	return selectedOptions[0]
-}
\ No newline at end of file
+}
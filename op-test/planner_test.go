@@ -0,0 +1,41 @@
+package op_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCartesian(t *testing.T) {
+	sels := []parameterSelection{
+		{Name: "net", Options: []string{"mainnet", "sepolia"}},
+		{Name: "client", Options: []string{"geth", "reth", "erigon"}},
+	}
+	got := cartesian(sels)
+	if len(got) != 6 {
+		t.Fatalf("expected 2x3 = 6 combinations, got %d: %v", len(got), got)
+	}
+
+	var combos []string
+	for _, combo := range got {
+		if len(combo) != 2 {
+			t.Fatalf("expected one option per axis, got %v", combo)
+		}
+		combos = append(combos, combo[0]+"/"+combo[1])
+	}
+	sort.Strings(combos)
+	want := []string{
+		"mainnet/erigon", "mainnet/geth", "mainnet/reth",
+		"sepolia/erigon", "sepolia/geth", "sepolia/reth",
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(combos, want) {
+		t.Fatalf("unexpected combinations:\ngot:  %v\nwant: %v", combos, want)
+	}
+}
+
+func TestCartesianEmpty(t *testing.T) {
+	if got := cartesian(nil); len(got) != 1 || len(got[0]) != 0 {
+		t.Fatalf("expected a single empty combination for no selections, got %v", got)
+	}
+}
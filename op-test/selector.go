@@ -0,0 +1,21 @@
+package op_test
+
+import "context"
+
+// parameterManagerCtxKey is the context key under which the active
+// ParameterSelector is stored (see Plan).
+type parameterManagerCtxKey struct{}
+
+// ParameterSelector decides which of the offered options for a named
+// parameter Select should proceed with. It is consulted once per parameter
+// per test-scope; the first returned option becomes the default (inline)
+// choice, and any further options are exhausted as sibling sub-tests.
+// Returning an empty slice skips the test.
+//
+// ctx carries every parameter choice already made in the current test-scope
+// (see parameterCtxKey), so a ParameterSelector can make cross-parameter
+// decisions, e.g. excluding a combination of an already-chosen parameter and
+// one of the options on offer now.
+type ParameterSelector interface {
+	Select(ctx context.Context, name string, options []string) []string
+}
@@ -0,0 +1,102 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// paramNamesCtxKey carries the names of every parameter selected so far
+// along the current scope's path, in no particular order (Snapshot sorts
+// them), so Snapshot can enumerate parameterCtxKey values that a plain
+// context.Context cannot otherwise list.
+type paramNamesCtxKey struct{}
+
+func paramNames(ctx context.Context) []string {
+	names, _ := ctx.Value(paramNamesCtxKey{}).([]string)
+	return names
+}
+
+// withParamName returns a context that also remembers name as selected,
+// without mutating any previously-handed-out context (each call stores a
+// fresh slice, the same way parameterCtxKey values are threaded).
+func withParamName(ctx context.Context, name string) context.Context {
+	existing := paramNames(ctx)
+	next := make([]string, len(existing), len(existing)+1)
+	copy(next, existing)
+	next = append(next, name)
+	return context.WithValue(ctx, paramNamesCtxKey{}, next)
+}
+
+// snapshotString renders every parameter chosen so far along ctx's path as
+// a deterministic identifier, e.g. "client=geth;fork=cancun;net=mainnet".
+// Parameters are sorted by name so the result is stable across runs even if
+// Select call order changes (e.g. under -op.parallel).
+func snapshotString(ctx context.Context) string {
+	names := paramNames(ctx)
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		if v, ok := ctx.Value(parameterCtxKey(name)).(string); ok {
+			values[name] = v
+		}
+	}
+	sorted := make([]string, 0, len(values))
+	for name := range values {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	parts := make([]string, len(sorted))
+	for i, name := range sorted {
+		parts[i] = name + "=" + values[name]
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseSnapshot parses a snapshot string of the form
+// "name1=value1;name2=value2" (as produced by Testing.Snapshot, and
+// accepted by -op.replay) into a name -> value map.
+func ParseSnapshot(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	if s == "" {
+		return out, nil
+	}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid snapshot entry %q, expected name=value", part)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// replayParameterSelector is the ParameterSelector installed by -op.replay:
+// it forces every parameter to the value recorded in a previous Snapshot,
+// so a single failing variant can be rerun exactly. A parameter not part of
+// the recorded combination falls back to the bare first-option default, so
+// replay doesn't need to enumerate axes the original run never reached.
+type replayParameterSelector struct {
+	recorded map[string]string
+}
+
+var _ ParameterSelector = (*replayParameterSelector)(nil)
+
+// Select implements ParameterSelector.Select
+func (s *replayParameterSelector) Select(_ context.Context, name string, options []string) []string {
+	value, ok := s.recorded[name]
+	if !ok {
+		if len(options) == 0 {
+			return nil
+		}
+		return options[:1]
+	}
+	for _, opt := range options {
+		if opt == value {
+			return []string{value}
+		}
+	}
+	// The recorded value isn't offered here any more: skip rather than
+	// silently diverge from the recorded combination.
+	return nil
+}
@@ -0,0 +1,58 @@
+package op_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseSnapshot(t *testing.T) {
+	got, err := ParseSnapshot("client=geth;fork=cancun;net=mainnet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"client": "geth", "fork": "cancun", "net": "mainnet"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if got, err := ParseSnapshot(""); err != nil || len(got) != 0 {
+		t.Fatalf("empty snapshot: got %v, err %v", got, err)
+	}
+
+	if _, err := ParseSnapshot("net"); err == nil {
+		t.Fatalf("expected error for entry missing '='")
+	}
+}
+
+func TestSnapshotString(t *testing.T) {
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, parameterCtxKey("net"), "mainnet")
+	ctx = withParamName(ctx, "net")
+	ctx = context.WithValue(ctx, parameterCtxKey("client"), "geth")
+	ctx = withParamName(ctx, "client")
+
+	got := snapshotString(ctx)
+	want := "client=geth;net=mainnet"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSnapshotStringStableRegardlessOfSelectOrder(t *testing.T) {
+	a := context.Background()
+	a = context.WithValue(a, parameterCtxKey("net"), "mainnet")
+	a = withParamName(a, "net")
+	a = context.WithValue(a, parameterCtxKey("client"), "geth")
+	a = withParamName(a, "client")
+
+	b := context.Background()
+	b = context.WithValue(b, parameterCtxKey("client"), "geth")
+	b = withParamName(b, "client")
+	b = context.WithValue(b, parameterCtxKey("net"), "mainnet")
+	b = withParamName(b, "net")
+
+	if snapshotString(a) != snapshotString(b) {
+		t.Fatalf("snapshotString should not depend on Select call order: %q vs %q", snapshotString(a), snapshotString(b))
+	}
+}
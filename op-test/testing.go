@@ -0,0 +1,50 @@
+package op_test
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Testing is the surface available at every test scope, whether it is
+// still being planned/recorded or is actually being executed.
+type Testing interface {
+	// Ctx returns the context scoped to the current test-scope, carrying
+	// all parametrization choices made thus far.
+	Ctx() context.Context
+	// Logger returns a logger for the current test-scope.
+	Logger() log.Logger
+	// Parameter looks up a parameter choice already made in the current
+	// test-scope (see Select).
+	Parameter(name string) (value string, ok bool)
+	// Select offers a named parameter with a set of options, and returns
+	// the option to proceed with. Repeat calls for the same parameter
+	// within a scope return the same choice.
+	Select(name string, options ...string) string
+	// Snapshot returns a deterministic identifier of every parameter
+	// choice made so far along this scope's path, e.g.
+	// "client=geth;fork=cancun;net=mainnet". Pass it to -op.replay to
+	// rerun exactly this combination.
+	Snapshot() string
+}
+
+// Planner can open new test scopes: nested plans that may branch further
+// (Plan), or leaf executions (Run).
+type Planner interface {
+	Testing
+	// Plan opens a nested test scope that may itself Plan or Run further.
+	Plan(name string, fn func(t Planner))
+	// Run opens a leaf test scope that executes test logic.
+	Run(name string, fn func(t Executor))
+	// RunParallel is like Run, but marks the leaf sub-test parallel via
+	// t.Parallel(), so it runs concurrently with its siblings (subject to
+	// the -test.parallel worker cap). Useful for expensive, independent
+	// leaves such as devnet or L1/L2 stack integration tests.
+	RunParallel(name string, fn func(t Executor))
+}
+
+// Executor is the Testing surface handed to a leaf test body opened by
+// Planner.Run.
+type Executor interface {
+	Testing
+}